@@ -19,47 +19,90 @@ package scheduling
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/samber/lo"
+	"golang.org/x/sync/singleflight"
 	v1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/apimachinery/pkg/util/sets"
 	csitranslation "k8s.io/csi-translation-lib"
 	"k8s.io/csi-translation-lib/plugins"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
 )
 
+// randomVolumeIDPrefixLength matches the upstream CSI volume-limit predicate's prefix length, see
+// https://github.com/kubernetes/kubernetes/blob/master/pkg/scheduler/framework/plugins/nodevolumelimits/csi.go
+const randomVolumeIDPrefixLength = 32
+
 //go:generate controller-gen object:headerFile="../../hack/boilerplate.go.txt" paths="."
 
 // translator is a CSI Translator that translates in-tree plugin names to their out-of-tree CSI driver names
 var translator = csitranslation.New()
 
+// VolumeMode distinguishes a persistent (attached) volume from an ephemeral, pod-local one. A CSI driver's
+// attach-count limit only applies to the persistent volumes it backs; ephemeral volumes are still tracked here
+// for reporting even when they're exempt from that limit.
+type VolumeMode string
+
+const (
+	VolumeModePersistent VolumeMode = "Persistent"
+	VolumeModeEphemeral  VolumeMode = "Ephemeral"
+)
+
+// VolumeSet tracks the pvcIDs associated with a single driver, split by VolumeMode.
+// +k8s:deepcopy-gen=true
+type VolumeSet struct {
+	Persistent sets.Set[string]
+	Ephemeral  sets.Set[string]
+}
+
+func newVolumeSet() VolumeSet {
+	return VolumeSet{Persistent: sets.New[string](), Ephemeral: sets.New[string]()}
+}
+
+func (s VolumeSet) forMode(mode VolumeMode) sets.Set[string] {
+	if mode == VolumeModeEphemeral {
+		return s.Ephemeral
+	}
+	return s.Persistent
+}
+
 // +k8s:deepcopy-gen=true
-type Volumes map[string]sets.Set[string]
+type Volumes map[string]VolumeSet
 
-func (u Volumes) Add(provisioner string, pvcID string) {
+func (u Volumes) Add(provisioner string, pvcID string, mode VolumeMode) {
 	existing, ok := u[provisioner]
 	if !ok {
-		existing = sets.New[string]()
+		existing = newVolumeSet()
 		u[provisioner] = existing
 	}
-	existing.Insert(pvcID)
+	existing.forMode(mode).Insert(pvcID)
 }
 
 func (u Volumes) Union(vol Volumes) Volumes {
 	cp := Volumes{}
 	for k, v := range u {
-		cp[k] = sets.New(sets.List(v)...)
+		cp[k] = VolumeSet{
+			Persistent: sets.New(sets.List(v.Persistent)...),
+			Ephemeral:  sets.New(sets.List(v.Ephemeral)...),
+		}
 	}
 	for k, v := range vol {
 		existing, ok := cp[k]
 		if !ok {
-			existing = sets.New[string]()
+			existing = newVolumeSet()
 			cp[k] = existing
 		}
-		existing.Insert(sets.List(v)...)
+		existing.Persistent.Insert(sets.List(v.Persistent)...)
+		existing.Ephemeral.Insert(sets.List(v.Ephemeral)...)
 	}
 	return cp
 }
@@ -68,111 +111,379 @@ func (u Volumes) Insert(volumes Volumes) {
 	for k, v := range volumes {
 		existing, ok := u[k]
 		if !ok {
-			existing = sets.New[string]()
+			existing = newVolumeSet()
 			u[k] = existing
 		}
-		existing.Insert(sets.List(v)...)
+		existing.Persistent.Insert(sets.List(v.Persistent)...)
+		existing.Ephemeral.Insert(sets.List(v.Ephemeral)...)
+	}
+}
+
+// PVCBindingMode describes how a pod volume's PersistentVolumeClaim relates to a PersistentVolume at scheduling
+// time.
+type PVCBindingMode int
+
+const (
+	// PVCBindingModeBound indicates the PVC is already bound to a PV (or uses immediate binding), so it can be
+	// tracked by its claim name without risk of being double-counted against other candidate nodes.
+	PVCBindingModeBound PVCBindingMode = iota
+	// PVCBindingModeWaitForFirstConsumer indicates the PVC's StorageClass delays binding until a node is chosen.
+	// The same claim will be simulated against many candidate nodes, so it must be tracked with a
+	// per-simulation identifier rather than its claim name.
+	PVCBindingModeWaitForFirstConsumer
+)
+
+// volumeResolverCacheTTL bounds how long a VolumeResolver reuses a PVC, PV, or StorageClass read before going
+// back to the API server. Karpenter's scheduling loop re-evaluates the same objects for many pods against many
+// candidate nodes within a single pass, so even a short TTL removes the bulk of the redundant traffic.
+const volumeResolverCacheTTL = 5 * time.Second
+
+type cacheEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+// objectCache is a minimal TTL cache keyed by object identity. It isn't meant to replace an informer, just to
+// collapse the burst of repeat lookups a single scheduling pass makes for the same object.
+type objectCache[K comparable, T any] struct {
+	mu    sync.RWMutex
+	items map[K]cacheEntry[T]
+	ttl   time.Duration
+}
+
+func newObjectCache[K comparable, T any](ttl time.Duration) *objectCache[K, T] {
+	return &objectCache[K, T]{items: map[K]cacheEntry[T]{}, ttl: ttl}
+}
+
+func (c *objectCache[K, T]) Get(key K) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.items[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero T
+		return zero, false
 	}
+	return entry.value, true
+}
+
+func (c *objectCache[K, T]) Set(key K, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = cacheEntry[T]{value: value, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// scDriverInfo is the result of resolving a StorageClass's provisioner to a CSI driver name, cached so the
+// provisioner→driver translation only happens once per StorageClass per volumeResolverCacheTTL.
+type scDriverInfo struct {
+	driverName  string
+	bindingMode PVCBindingMode
+}
+
+// VolumeResolver resolves the CSI driver backing a pod's volumes. It caches PVC, PV, and StorageClass reads and
+// deduplicates concurrent lookups with singleflight so the scheduler's hot path doesn't issue a Get per volume
+// per pod per candidate node. A VolumeResolver is meant to live for the duration of a single scheduling pass.
+type VolumeResolver struct {
+	kubeClient client.Reader
+	group      singleflight.Group
+
+	pvcCache *objectCache[client.ObjectKey, v1.PersistentVolumeClaim]
+	pvCache  *objectCache[string, v1.PersistentVolume]
+	scCache  *objectCache[string, scDriverInfo]
+
+	defaultStorageClassNameOnce sync.Once
+	defaultStorageClassName     string
+	defaultStorageClassErr      error
+}
+
+func NewVolumeResolver(kubeClient client.Reader) *VolumeResolver {
+	return &VolumeResolver{
+		kubeClient: kubeClient,
+		pvcCache:   newObjectCache[client.ObjectKey, v1.PersistentVolumeClaim](volumeResolverCacheTTL),
+		pvCache:    newObjectCache[string, v1.PersistentVolume](volumeResolverCacheTTL),
+		scCache:    newObjectCache[string, scDriverInfo](volumeResolverCacheTTL),
+	}
+}
+
+// defaultStorageClassNameFor resolves the cluster's default StorageClass once per VolumeResolver (i.e. once per
+// scheduling pass) instead of once per pod.
+func (r *VolumeResolver) defaultStorageClassNameFor(ctx context.Context) (string, error) {
+	r.defaultStorageClassNameOnce.Do(func() {
+		r.defaultStorageClassName, r.defaultStorageClassErr = DiscoverDefaultStorageClassName(ctx, r.kubeClient)
+	})
+	return r.defaultStorageClassName, r.defaultStorageClassErr
 }
 
 //nolint:gocyclo
-func GetVolumes(ctx context.Context, kubeClient client.Client, pod *v1.Pod) (Volumes, error) {
+func (r *VolumeResolver) GetVolumes(ctx context.Context, pod *v1.Pod, randomVolumeIDPrefix string) (Volumes, error) {
 	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("pod", pod.Name))
 	podPVCs := Volumes{}
-	defaultStorageClassName, err := DiscoverDefaultStorageClassName(ctx, kubeClient)
+	defaultStorageClassName, err := r.defaultStorageClassNameFor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("discovering default storage class, %w", err)
 	}
 	for _, volume := range pod.Spec.Volumes {
 		ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("volume", volume.Name))
 		var pvcID, storageClassName, volumeName string
-		var pvc v1.PersistentVolumeClaim
-		if volume.PersistentVolumeClaim != nil {
-			if err = kubeClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: volume.PersistentVolumeClaim.ClaimName}, &pvc); err != nil {
+		var mode VolumeMode
+		var inlineCSI *v1.CSIVolumeSource
+		switch {
+		case volume.PersistentVolumeClaim != nil:
+			pvc, err := r.getPVC(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: volume.PersistentVolumeClaim.ClaimName})
+			if err != nil {
 				return nil, err
 			}
 			pvcID = fmt.Sprintf("%s/%s", pod.Namespace, volume.PersistentVolumeClaim.ClaimName)
 			storageClassName = lo.FromPtr(pvc.Spec.StorageClassName)
 			volumeName = pvc.Spec.VolumeName
-		} else if volume.Ephemeral != nil {
+			mode = VolumeModePersistent
+		case volume.Ephemeral != nil:
 			// generated name per https://kubernetes.io/docs/concepts/storage/ephemeral-volumes/#persistentvolumeclaim-naming
 			pvcID = fmt.Sprintf("%s/%s-%s", pod.Namespace, pod.Name, volume.Name)
 			storageClassName = lo.FromPtr(volume.Ephemeral.VolumeClaimTemplate.Spec.StorageClassName)
 			volumeName = volume.Ephemeral.VolumeClaimTemplate.Spec.VolumeName
-		} else {
+			// Despite the name, a generic ephemeral volume is dynamically provisioned into a real PV and attached
+			// to the node like any other persistent volume; it still consumes the driver's attach slot. Only CSI
+			// inline volumes (below) are genuinely pod-local. Drivers that are truly ephemeral-only are exempted
+			// from the attach limit separately, via ephemeralOnlyDrivers.
+			mode = VolumeModePersistent
+		case volume.CSI != nil:
+			// A CSI inline ephemeral volume has no backing PVC/PV at all; the driver is named directly on the
+			// pod volume and the volume is always pod-local.
+			pvcID = fmt.Sprintf("%s/%s-%s", pod.Namespace, pod.Name, volume.Name)
+			mode = VolumeModeEphemeral
+			inlineCSI = volume.CSI
+		default:
 			continue
 		}
 		if storageClassName == "" {
 			storageClassName = defaultStorageClassName
 		}
-		driverName, err := resolveDriver(ctx, kubeClient, volumeName, storageClassName)
+		driverName, bindingMode, err := r.resolveDriver(ctx, volumeName, storageClassName, inlineCSI)
 		if err != nil {
 			return nil, err
 		}
 		// might be a non-CSI driver, something we don't currently handle
 		if driverName != "" {
-			podPVCs.Add(driverName, pvcID)
+			// The claim hasn't been bound yet and won't be until a node is chosen, so every candidate node we
+			// simulate this pod against needs its own identifier. Re-evaluating the same pod against the same
+			// simulated node must still produce the same ID, so the prefix (not the ID itself) is randomized.
+			if volumeName == "" && bindingMode == PVCBindingModeWaitForFirstConsumer {
+				pvcID = fmt.Sprintf("%s-%s", randomVolumeIDPrefix, pvcID)
+			}
+			podPVCs.Add(driverName, pvcID, mode)
 		}
 	}
 	return podPVCs, nil
 }
 
 // resolveDriver resolves the storage driver name in the following order:
-//  1. If the PV associated with the pod volume is using CSI.driver in its spec, then use that name
-//  2. If the StorageClass associated with the PV has a Provisioner
-func resolveDriver(ctx context.Context, kubeClient client.Client, volumeName string, storageClassName string) (string, error) {
+//  1. If the pod volume is a CSI inline ephemeral volume, use its Driver directly
+//  2. If the PV associated with the pod volume is using CSI.driver in its spec, then use that name
+//  3. If the StorageClass associated with the PV has a Provisioner
+//
+// It also reports a PVCBindingMode so callers can tell whether this volume is already bound (and countable by claim
+// name) or will be provisioned once a node is picked: PVCBindingModeBound when the driver comes from an inline
+// volume or from the PV, otherwise the binding mode of the associated StorageClass.
+func (r *VolumeResolver) resolveDriver(ctx context.Context, volumeName string, storageClassName string, inlineCSI *v1.CSIVolumeSource) (string, PVCBindingMode, error) {
+	if inlineCSI != nil {
+		return inlineCSI.Driver, PVCBindingModeBound, nil
+	}
 	// We can track the volume usage by the CSI Driver name which is pulled from the storage class for dynamic
 	// volumes, or if it's bound/static we can pull the volume name
 	if volumeName != "" {
-		driverName, err := driverFromVolume(ctx, kubeClient, volumeName)
+		driverName, err := r.driverFromVolume(ctx, volumeName)
 		if err != nil {
-			return "", err
+			return "", PVCBindingModeBound, err
 		}
 		if driverName != "" {
-			return driverName, nil
+			return driverName, PVCBindingModeBound, nil
 		}
 	}
 	if storageClassName != "" {
-		driverName, err := driverFromSC(ctx, kubeClient, storageClassName)
+		info, err := r.driverFromSC(ctx, storageClassName)
 		if err != nil {
-			return "", err
+			return "", PVCBindingModeBound, err
 		}
-		if driverName != "" {
-			return driverName, nil
+		if info.driverName != "" {
+			return info.driverName, info.bindingMode, nil
 		}
 	}
 	// Driver name wasn't able to resolve for this volume. In this case, we just ignore the
 	// volume and move on to the other volumes that the pod has
-	return "", nil
+	return "", PVCBindingModeBound, nil
 }
 
-// driverFromSC resolves the storage driver name by getting the Provisioner name from the StorageClass
-func driverFromSC(ctx context.Context, kubeClient client.Client, storageClassName string) (string, error) {
-	var sc storagev1.StorageClass
-	if err := kubeClient.Get(ctx, client.ObjectKey{Name: storageClassName}, &sc); err != nil {
-		return "", err
+// driverFromSC resolves the storage driver name by getting the Provisioner name from the StorageClass. In-tree
+// provisioner names (e.g. kubernetes.io/aws-ebs) are translated to their out-of-tree CSI driver name so that
+// volume usage is tracked against the same driver name that CSINode/CSIDriver (and therefore VolumeUsage.limits)
+// key on. The StorageClass's VolumeBindingMode is also returned so callers can detect WaitForFirstConsumer claims.
+// The result is cached for volumeResolverCacheTTL, so the translation is only recomputed once the entry expires.
+func (r *VolumeResolver) driverFromSC(ctx context.Context, storageClassName string) (scDriverInfo, error) {
+	if info, ok := r.scCache.Get(storageClassName); ok {
+		return info, nil
 	}
-	// Check if the provisioner name is an in-tree plugin name
-	if csiName, err := translator.GetCSINameFromInTreeName(sc.Provisioner); err == nil {
-		return csiName, nil
+	v, err, _ := r.group.Do("sc/"+storageClassName, func() (interface{}, error) {
+		var sc storagev1.StorageClass
+		if err := r.kubeClient.Get(ctx, client.ObjectKey{Name: storageClassName}, &sc); err != nil {
+			return scDriverInfo{}, err
+		}
+		info := scDriverInfo{driverName: sc.Provisioner, bindingMode: PVCBindingModeBound}
+		if lo.FromPtr(sc.VolumeBindingMode) == storagev1.VolumeBindingWaitForFirstConsumer {
+			info.bindingMode = PVCBindingModeWaitForFirstConsumer
+		}
+		if translator.IsMigratableIntreePluginByName(sc.Provisioner) {
+			csiName, err := translator.GetCSINameFromInTreeName(sc.Provisioner)
+			if err != nil {
+				return scDriverInfo{}, fmt.Errorf("translating in-tree provisioner %q to CSI name, %w", sc.Provisioner, err)
+			}
+			info.driverName = csiName
+		}
+		r.scCache.Set(storageClassName, info)
+		return info, nil
+	})
+	if err != nil {
+		return scDriverInfo{}, err
 	}
-	return sc.Provisioner, nil
+	return v.(scDriverInfo), nil
 }
 
-// driverFromVolume resolves the storage driver name by getting the CSI spec from inside the PersistentVolume
-func driverFromVolume(ctx context.Context, kubeClient client.Client, volumeName string) (string, error) {
-	var pv v1.PersistentVolume
-	if err := kubeClient.Get(ctx, client.ObjectKey{Name: volumeName}, &pv); err != nil {
+// driverFromVolume resolves the storage driver name by getting the CSI spec from inside the PersistentVolume. PVs
+// that still use an in-tree volume source (AWS EBS, GCE PD, Azure Disk/File, Cinder, vSphere, Portworx, RBD, etc.)
+// are translated to their CSI equivalent first so callers never have to special-case individual in-tree plugins.
+func (r *VolumeResolver) driverFromVolume(ctx context.Context, volumeName string) (string, error) {
+	pv, err := r.getPV(ctx, volumeName)
+	if err != nil {
 		return "", err
 	}
 	if pv.Spec.CSI != nil {
 		return pv.Spec.CSI.Driver, nil
-	} else if pv.Spec.AWSElasticBlockStore != nil {
-		return plugins.AWSEBSDriverName, nil
+	}
+	if translator.IsPVMigratable(&pv) {
+		translatedPV, err := translator.TranslateInTreePVToCSI(&pv)
+		if err != nil {
+			return "", fmt.Errorf("translating in-tree volume %q to CSI, %w", volumeName, err)
+		}
+		if translatedPV.Spec.CSI != nil {
+			return translatedPV.Spec.CSI.Driver, nil
+		}
 	}
 	return "", nil
 }
 
+func (r *VolumeResolver) getPVC(ctx context.Context, key client.ObjectKey) (v1.PersistentVolumeClaim, error) {
+	if pvc, ok := r.pvcCache.Get(key); ok {
+		return pvc, nil
+	}
+	v, err, _ := r.group.Do("pvc/"+key.String(), func() (interface{}, error) {
+		var pvc v1.PersistentVolumeClaim
+		if err := r.kubeClient.Get(ctx, key, &pvc); err != nil {
+			return v1.PersistentVolumeClaim{}, err
+		}
+		r.pvcCache.Set(key, pvc)
+		return pvc, nil
+	})
+	if err != nil {
+		return v1.PersistentVolumeClaim{}, err
+	}
+	return v.(v1.PersistentVolumeClaim), nil
+}
+
+func (r *VolumeResolver) getPV(ctx context.Context, name string) (v1.PersistentVolume, error) {
+	if pv, ok := r.pvCache.Get(name); ok {
+		return pv, nil
+	}
+	v, err, _ := r.group.Do("pv/"+name, func() (interface{}, error) {
+		var pv v1.PersistentVolume
+		if err := r.kubeClient.Get(ctx, client.ObjectKey{Name: name}, &pv); err != nil {
+			return v1.PersistentVolume{}, err
+		}
+		r.pvCache.Set(name, pv)
+		return pv, nil
+	})
+	if err != nil {
+		return v1.PersistentVolume{}, err
+	}
+	return v.(v1.PersistentVolume), nil
+}
+
+// wellKnownInTreeVolumeLimits holds the attach-limit defaults the in-tree volume plugins themselves enforced
+// before migrating to CSI. They're used as a last resort when neither the node's CSINode object nor the instance
+// type declare a limit for a migrated driver.
+var wellKnownInTreeVolumeLimits = map[string]int{
+	plugins.AWSEBSDriverName:    39,
+	plugins.GCEPDDriverName:     16,
+	plugins.AzureDiskDriverName: 8,
+}
+
+// inTreeAttachLimitResourceNames maps a CSI driver name to the node capacity resource name its in-tree
+// predecessor advertised (e.g. "attachable-volumes-aws-ebs"), so an instance type that still declares its limits
+// under the legacy resource name is honored.
+var inTreeAttachLimitResourceNames = map[string]v1.ResourceName{
+	plugins.AWSEBSDriverName:    "attachable-volumes-aws-ebs",
+	plugins.GCEPDDriverName:     "attachable-volumes-gce-pd",
+	plugins.AzureDiskDriverName: "attachable-volumes-azure-disk",
+}
+
+// supportsPersistentLifecycleMode reports whether a CSIDriver's VolumeLifecycleModes include Persistent.
+// VolumeLifecycleModes defaults to [Persistent] when unset.
+func supportsPersistentLifecycleMode(modes []storagev1.VolumeLifecycleMode) bool {
+	if len(modes) == 0 {
+		return true
+	}
+	return lo.Contains(modes, storagev1.VolumeLifecyclePersistent)
+}
+
+// BuildVolumeLimits constructs a VolumeUsage whose limits are populated from the cluster's CSIDriver objects and,
+// where available, the node's CSINode object. CSINode.Spec.Drivers[].Allocatable.Count is authoritative once the
+// node has registered with the driver; until then (or if the node has no CSINode entry for a driver) we fall back,
+// for the migrated drivers in inTreeAttachLimitResourceNames only, to the legacy attach-limit resource the instance
+// type declares, and finally to the well-known in-tree limit. Other drivers without a CSINode entry get no limit.
+// Drivers with AttachRequired=false get no limit. Drivers whose VolumeLifecycleModes don't include Persistent are marked as ephemeral-only so
+// ExceedsLimits never holds their volumes against the node's attach limit.
+func BuildVolumeLimits(ctx context.Context, kubeClient client.Client, nodeName string, instanceType *cloudprovider.InstanceType) (*VolumeUsage, error) {
+	volumeUsage := NewVolumeUsage()
+
+	var csiDriverList storagev1.CSIDriverList
+	if err := kubeClient.List(ctx, &csiDriverList); err != nil {
+		return nil, fmt.Errorf("listing csi drivers, %w", err)
+	}
+
+	var csiNode storagev1.CSINode
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: nodeName}, &csiNode); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("getting csi node %q, %w", nodeName, err)
+	}
+	allocatable := map[string]int{}
+	for _, driver := range csiNode.Spec.Drivers {
+		if driver.Allocatable != nil && driver.Allocatable.Count != nil {
+			allocatable[driver.Name] = int(*driver.Allocatable.Count)
+		}
+	}
+
+	for _, csiDriver := range csiDriverList.Items {
+		if !supportsPersistentLifecycleMode(csiDriver.Spec.VolumeLifecycleModes) {
+			volumeUsage.markEphemeralOnly(csiDriver.Name)
+		}
+		// Drivers that don't require an attach (AttachRequired=false) never advertise (or need) an attach limit.
+		if !lo.FromPtrOr(csiDriver.Spec.AttachRequired, true) {
+			continue
+		}
+		if limit, ok := allocatable[csiDriver.Name]; ok {
+			volumeUsage.AddLimit(csiDriver.Name, limit)
+			continue
+		}
+		if resourceName, ok := inTreeAttachLimitResourceNames[csiDriver.Name]; ok && instanceType != nil {
+			if quantity, ok := instanceType.Capacity[resourceName]; ok {
+				volumeUsage.AddLimit(csiDriver.Name, int(quantity.Value()))
+				continue
+			}
+		}
+		if limit, ok := wellKnownInTreeVolumeLimits[csiDriver.Name]; ok {
+			volumeUsage.AddLimit(csiDriver.Name, limit)
+		}
+	}
+	return volumeUsage, nil
+}
+
 // VolumeUsage tracks volume limits on a per node basis.  The number of volumes that can be mounted varies by instance
 // type. We need to be aware and track the mounted volume usage to inform our awareness of which pods can schedule to
 // which nodes.
@@ -181,20 +492,45 @@ type VolumeUsage struct {
 	volumes    Volumes
 	podVolumes map[types.NamespacedName]Volumes
 	limits     map[string]int
+	// ephemeralOnlyDrivers holds the drivers whose VolumeLifecycleModes don't include Persistent. Their volumes
+	// are still tracked in volumes/podVolumes for reporting, but never held against the attach limit in limits.
+	ephemeralOnlyDrivers map[string]bool
+	// randomVolumeIDPrefix is unique per simulated node (a Copy keeps its source's prefix) so that WaitForFirstConsumer PVCs,
+	// which aren't bound yet, don't collide across different candidate nodes while still colliding correctly when
+	// the same pod is re-evaluated against the same simulated node.
+	randomVolumeIDPrefix string
 }
 
 func NewVolumeUsage() *VolumeUsage {
 	return &VolumeUsage{
-		volumes:    Volumes{},
-		podVolumes: map[types.NamespacedName]Volumes{},
-		limits:     map[string]int{},
+		volumes:              Volumes{},
+		podVolumes:           map[types.NamespacedName]Volumes{},
+		limits:               map[string]int{},
+		ephemeralOnlyDrivers: map[string]bool{},
+		randomVolumeIDPrefix: rand.String(randomVolumeIDPrefixLength),
 	}
 }
 
+// RandomVolumeIDPrefix returns the prefix used to disambiguate unbound WaitForFirstConsumer PVCs tracked by this
+// VolumeUsage. Callers pass this to GetVolumes when resolving the volumes for a pod being simulated against the
+// node this VolumeUsage represents.
+func (v *VolumeUsage) RandomVolumeIDPrefix() string {
+	return v.randomVolumeIDPrefix
+}
+
+// markEphemeralOnly records that a driver only supports the Ephemeral CSI lifecycle mode, so ExceedsLimits should
+// never hold its volumes against the node's attach limit.
+func (v *VolumeUsage) markEphemeralOnly(driverName string) {
+	v.ephemeralOnlyDrivers[driverName] = true
+}
+
 func (v *VolumeUsage) ExceedsLimits(vols Volumes) error {
-	for k, volumes := range v.volumes.Union(vols) {
-		if limit, hasLimit := v.limits[k]; hasLimit && len(volumes) > limit {
-			return fmt.Errorf("would exceed volume limit for %s, %d > %d", k, len(volumes), limit)
+	for k, volumeSet := range v.volumes.Union(vols) {
+		if v.ephemeralOnlyDrivers[k] {
+			continue
+		}
+		if limit, hasLimit := v.limits[k]; hasLimit && volumeSet.Persistent.Len() > limit {
+			return fmt.Errorf("would exceed volume limit for %s, %d > %d", k, volumeSet.Persistent.Len(), limit)
 		}
 	}
 	return nil
@@ -217,3 +553,25 @@ func (v *VolumeUsage) DeletePod(key types.NamespacedName) {
 		v.volumes.Insert(c)
 	}
 }
+
+// Copy returns a deep copy of the VolumeUsage so a scheduling simulation can fork volume usage state for a
+// candidate node cheaply, without having to rebuild it from a full Union of every pod's volumes.
+func (v *VolumeUsage) Copy() *VolumeUsage {
+	cp := &VolumeUsage{
+		volumes:              v.volumes.Union(Volumes{}),
+		podVolumes:           make(map[types.NamespacedName]Volumes, len(v.podVolumes)),
+		limits:               make(map[string]int, len(v.limits)),
+		ephemeralOnlyDrivers: make(map[string]bool, len(v.ephemeralOnlyDrivers)),
+		randomVolumeIDPrefix: v.randomVolumeIDPrefix,
+	}
+	for key, volumes := range v.podVolumes {
+		cp.podVolumes[key] = volumes.Union(Volumes{})
+	}
+	for driver, limit := range v.limits {
+		cp.limits[driver] = limit
+	}
+	for driver, ephemeralOnly := range v.ephemeralOnlyDrivers {
+		cp.ephemeralOnlyDrivers[driver] = ephemeralOnly
+	}
+	return cp
+}
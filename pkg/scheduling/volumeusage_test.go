@@ -0,0 +1,218 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduling
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+)
+
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = v1.AddToScheme(scheme)
+	_ = storagev1.AddToScheme(scheme)
+	return scheme
+}
+
+func TestExceedsLimits(t *testing.T) {
+	cases := []struct {
+		name       string
+		driver     string
+		limit      int
+		ephemeral  bool
+		persistent int
+		ephemeralN int
+		wantErr    bool
+	}{
+		{name: "persistent under limit", driver: "ebs.csi.aws.com", limit: 2, persistent: 1, wantErr: false},
+		{name: "persistent at limit", driver: "ebs.csi.aws.com", limit: 2, persistent: 2, wantErr: false},
+		{name: "persistent over limit", driver: "ebs.csi.aws.com", limit: 2, persistent: 3, wantErr: true},
+		{name: "inline CSI ephemeral volumes on a persistent-capable driver are not counted", driver: "ebs.csi.aws.com", limit: 2, ephemeralN: 5, wantErr: false},
+		{name: "ephemeral-only driver is exempt regardless of count", driver: "efs.csi.aws.com", limit: 1, ephemeral: true, ephemeralN: 5, wantErr: false},
+		{name: "no limit configured never exceeds", driver: "ebs.csi.aws.com", persistent: 100, wantErr: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			usage := NewVolumeUsage()
+			if tc.limit > 0 {
+				usage.AddLimit(tc.driver, tc.limit)
+			}
+			if tc.ephemeral {
+				usage.markEphemeralOnly(tc.driver)
+			}
+			vols := Volumes{}
+			for i := 0; i < tc.persistent; i++ {
+				vols.Add(tc.driver, string(rune('a'+i)), VolumeModePersistent)
+			}
+			for i := 0; i < tc.ephemeralN; i++ {
+				vols.Add(tc.driver, string(rune('a'+i)), VolumeModeEphemeral)
+			}
+			err := usage.ExceedsLimits(vols)
+			if tc.wantErr && err == nil {
+				t.Fatalf("expected ExceedsLimits to return an error, got nil")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("expected ExceedsLimits to return nil, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGetVolumesGenericEphemeralCountsAsPersistent(t *testing.T) {
+	ctx := context.Background()
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-pod"},
+		Spec: v1.PodSpec{
+			Volumes: []v1.Volume{
+				{
+					Name: "scratch",
+					VolumeSource: v1.VolumeSource{
+						Ephemeral: &v1.EphemeralVolumeSource{
+							VolumeClaimTemplate: &v1.PersistentVolumeClaimTemplate{
+								Spec: v1.PersistentVolumeClaimSpec{StorageClassName: ptrTo("ebs-sc")},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "ebs-sc"},
+		Provisioner: "ebs.csi.aws.com",
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(sc).Build()
+	resolver := NewVolumeResolver(kubeClient)
+
+	vols, err := resolver.GetVolumes(ctx, pod, "prefix")
+	if err != nil {
+		t.Fatalf("GetVolumes returned an error: %v", err)
+	}
+	volumeSet, ok := vols["ebs.csi.aws.com"]
+	if !ok {
+		t.Fatalf("expected a tracked volume for driver ebs.csi.aws.com, got %v", vols)
+	}
+	if volumeSet.Persistent.Len() != 1 {
+		t.Fatalf("expected the generic ephemeral volume to be tracked as Persistent, got Persistent=%d Ephemeral=%d",
+			volumeSet.Persistent.Len(), volumeSet.Ephemeral.Len())
+	}
+	if volumeSet.Ephemeral.Len() != 0 {
+		t.Fatalf("did not expect any Ephemeral entries, got %d", volumeSet.Ephemeral.Len())
+	}
+}
+
+func TestGetVolumesWaitForFirstConsumerIDsAreStableAndUnique(t *testing.T) {
+	ctx := context.Background()
+	newPod := func() *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-pod"},
+			Spec: v1.PodSpec{
+				Volumes: []v1.Volume{
+					{
+						Name: "data",
+						VolumeSource: v1.VolumeSource{
+							PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data-claim"},
+						},
+					},
+				},
+			},
+		}
+	}
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "data-claim"},
+		Spec:       v1.PersistentVolumeClaimSpec{StorageClassName: ptrTo("wfc-sc")},
+	}
+	bindingMode := storagev1.VolumeBindingWaitForFirstConsumer
+	sc := &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: "wfc-sc"},
+		Provisioner:       "ebs.csi.aws.com",
+		VolumeBindingMode: &bindingMode,
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(pvc, sc).Build()
+
+	// Re-evaluating the same pod with the same randomVolumeIDPrefix (i.e. the same simulated node) must produce the same ID.
+	resolver := NewVolumeResolver(kubeClient)
+	first, err := resolver.GetVolumes(ctx, newPod(), "node-a-prefix")
+	if err != nil {
+		t.Fatalf("GetVolumes returned an error: %v", err)
+	}
+	second, err := resolver.GetVolumes(ctx, newPod(), "node-a-prefix")
+	if err != nil {
+		t.Fatalf("GetVolumes returned an error: %v", err)
+	}
+	firstID := sets.List(first["ebs.csi.aws.com"].Persistent)[0]
+	secondID := sets.List(second["ebs.csi.aws.com"].Persistent)[0]
+	if firstID != secondID {
+		t.Fatalf("expected re-evaluating the same pod against the same node to produce the same ID, got %q and %q", firstID, secondID)
+	}
+
+	// A different candidate node (a different randomVolumeIDPrefix) must not collide with the first.
+	otherResolver := NewVolumeResolver(kubeClient)
+	other, err := otherResolver.GetVolumes(ctx, newPod(), "node-b-prefix")
+	if err != nil {
+		t.Fatalf("GetVolumes returned an error: %v", err)
+	}
+	otherID := sets.List(other["ebs.csi.aws.com"].Persistent)[0]
+	if otherID == firstID {
+		t.Fatalf("expected different candidate nodes to produce different WaitForFirstConsumer IDs, both got %q", firstID)
+	}
+}
+
+func TestBuildVolumeLimitsNilInstanceType(t *testing.T) {
+	ctx := context.Background()
+	csiDriver := &storagev1.CSIDriver{ObjectMeta: metav1.ObjectMeta{Name: "ebs.csi.aws.com"}}
+	kubeClient := fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(csiDriver).Build()
+
+	usage, err := BuildVolumeLimits(ctx, kubeClient, "test-node", nil)
+	if err != nil {
+		t.Fatalf("expected a nil instanceType to fall back instead of erroring, got %v", err)
+	}
+	if limit, ok := usage.limits["ebs.csi.aws.com"]; !ok || limit != 39 {
+		t.Fatalf("expected BuildVolumeLimits to fall back to the well-known in-tree limit, got %v (ok=%v)", limit, ok)
+	}
+}
+
+func TestBuildVolumeLimitsFallsBackToInstanceTypeCapacity(t *testing.T) {
+	ctx := context.Background()
+	csiDriver := &storagev1.CSIDriver{ObjectMeta: metav1.ObjectMeta{Name: "ebs.csi.aws.com"}}
+	kubeClient := fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(csiDriver).Build()
+	instanceType := &cloudprovider.InstanceType{
+		Capacity: v1.ResourceList{"attachable-volumes-aws-ebs": *resource.NewQuantity(25, resource.DecimalSI)},
+	}
+
+	usage, err := BuildVolumeLimits(ctx, kubeClient, "test-node", instanceType)
+	if err != nil {
+		t.Fatalf("BuildVolumeLimits returned an error: %v", err)
+	}
+	limit, ok := usage.limits["ebs.csi.aws.com"]
+	if !ok || limit != 25 {
+		t.Fatalf("expected the translated driver's limit to come from instance type capacity, got %v (ok=%v)", limit, ok)
+	}
+}
+
+func ptrTo[T any](v T) *T { return &v }